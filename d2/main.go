@@ -10,6 +10,8 @@ Example Usages:
 	dagger call --file=your-file.d2 render export --path=./out
 	dagger call --format='pdf' --file='your-file.d2' render export --path=./out
 	dagger call --format='gif' --file='your-file.d2' with-arg --arg='--animate-interval=100' render export --path=./out
+	dagger call serve --dir=./diagrams up
+	dagger call watch-export --dir=./diagrams --out-host-path=./out --debounce-ms=500
 
 For more details, refer to the README.md.
 */
@@ -21,13 +23,17 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 )
 
 type D2 struct {
-	File   *dagger.File      // +private
-	Dir    *dagger.Directory // +private
-	Format Format            // +private
-	Args   []string          // +private, extra arguments for d2 command
+	File         *dagger.File      // +private
+	Dir          *dagger.Directory // +private
+	Format       Format            // +private
+	Args         []string          // +private, extra arguments for d2 command
+	LayoutEngine string            // +private, --layout
+	Theme        int               // +private, --theme
+	Sketch       bool              // +private, --sketch
 }
 
 // format https://d2lang.com/tour/formats
@@ -67,47 +73,188 @@ func (m *D2) WithFrmat(format Format) *D2 {
 	return m
 }
 
-// This doesn't work, because the file is uploaded in the session and changes from the host machine are not sent
-// +private
+// sets the layout engine d2 uses to position shapes, e.g. "dagre", "elk" or "tala"
+func (m *D2) WithLayoutEngine(engine string) *D2 {
+	m.LayoutEngine = engine
+	return m
+}
+
+// sets the numeric ID of the built-in theme to render with, see https://d2lang.com/tour/themes
+func (m *D2) WithTheme(id int) *D2 {
+	m.Theme = id
+	return m
+}
+
+// renders diagrams in d2's hand-drawn "sketch" style
+func (m *D2) WithSketch(sketch bool) *D2 {
+	m.Sketch = sketch
+	return m
+}
+
+// sharedArgs returns the builder-configured flags (layout/theme/sketch) ahead of any ad-hoc WithArg values.
+func (m *D2) sharedArgs() []string {
+	args := make([]string, 0, len(m.Args)+4)
+
+	if m.LayoutEngine != "" {
+		args = append(args, "--layout", m.LayoutEngine)
+	}
+
+	if m.Theme != 0 {
+		args = append(args, "--theme", fmt.Sprintf("%d", m.Theme))
+	}
+
+	if m.Sketch {
+		args = append(args, "--sketch")
+	}
+
+	return append(args, m.Args...)
+}
+
+// watchEntry resolves which .d2 file d2 --watch should be pointed at: entry itself if given,
+// or the sole .d2 file in dir if there's exactly one. d2 --watch only takes a single input, so a
+// directory with more than one top-level .d2 file requires entry to disambiguate.
+func watchEntry(ctx context.Context, dir *dagger.Directory, entry string) (string, error) {
+	if entry != "" {
+		return entry, nil
+	}
+
+	names, err := dir.Entries(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list watched directory: %w", err)
+	}
+
+	var d2Files []string
+	for _, name := range names {
+		if strings.HasSuffix(name, ".d2") {
+			d2Files = append(d2Files, name)
+		}
+	}
+
+	switch len(d2Files) {
+	case 0:
+		return "", errors.New("no .d2 files found in directory")
+	case 1:
+		return d2Files[0], nil
+	default:
+		return "", fmt.Errorf("directory contains %d .d2 files (%s); pass entry to select which one to watch", len(d2Files), strings.Join(d2Files, ", "))
+	}
+}
+
+// watchContainer builds the container running `d2 --watch <entry>`, shared by Serve (which turns
+// it into a long-running Service) and WatchExport (which polls it for rendered output).
+func (m *D2) watchContainer(dir *dagger.Directory, entry string, port int, host string) *dagger.Container {
+	args := append([]string{"d2", "--watch", fmt.Sprintf("--port=%d", port), fmt.Sprintf("--host=%s", host)}, m.sharedArgs()...)
+	args = append(args, fmt.Sprintf("/d2/in/%s", entry))
+
+	return container(SVG).
+		WithWorkdir("/d2").
+		WithMountedDirectory("./in", dir).
+		WithExposedPort(port, dagger.ContainerWithExposedPortOpts{
+			Protocol:                    "TCP",
+			Description:                 "The HTTP+WebSocket live-reload endpoint served by d2 --watch",
+			ExperimentalSkipHealthcheck: true,
+		}).
+		WithExec(args)
+}
+
+// serves a single .d2 file within dir with `d2 --watch`, which live-recompiles its SVG and pushes
+// updates to connected browsers over its built-in HTTP+WebSocket endpoint whenever the file
+// changes. entry names the file to watch, and is required unless dir contains exactly one .d2
+// file, since d2 --watch only ever watches a single entry.
 func (m *D2) Serve(
-	file *dagger.File,
+	ctx context.Context,
+	// The directory of .d2 files to watch
+	dir *dagger.Directory,
+	// The .d2 file within dir to watch
+	// +optional
+	entry string,
 	// +optional
 	// +default=9000
 	port int,
 	// +optional
 	// +default="0.0.0.0"
-	host string) *dagger.Service {
+	host string,
+) (*dagger.Service, error) {
 
-	return container(PNG).
-		WithWorkdir("/d2").
-		WithMountedFile("./in/in.d2", file).
-		WithWorkdir("./in").
-		WithEnvVariable("PORT", fmt.Sprintf("%d", port)).
-		WithEnvVariable("HOST", host).
-		WithExposedPort(port, dagger.ContainerWithExposedPortOpts{
-			Protocol:                    "TCP",
-			Description:                 "The port D2 listens on",
-			ExperimentalSkipHealthcheck: true,
-		}).
+	resolvedEntry, err := watchEntry(ctx, dir, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.watchContainer(dir, resolvedEntry, port, host).
 		AsService(dagger.ContainerAsServiceOpts{
-			Args:                          []string{"sh", "-c", "d2 --watch --port $PORT --host $HOST in.d2 out.svg"},
-			UseEntrypoint:                 false,
+			UseEntrypoint:                 true,
 			ExperimentalPrivilegedNesting: false,
 			InsecureRootCapabilities:      false,
 			Expand:                        true,
 			NoInit:                        false,
-		})
+		}), nil
+}
+
+// watches the single .d2 entry within dir for changes, exporting the rendered SVG served by
+// Serve's live-reload server to outHostPath every debounceMs until ctx is cancelled. It polls the
+// actual running service over the network (via a service binding), rather than re-exporting the
+// unrendered input mount.
+func (m *D2) WatchExport(
+	ctx context.Context,
+	// The directory of .d2 files to watch
+	dir *dagger.Directory,
+	// The .d2 file within dir to watch
+	// +optional
+	entry string,
+	// The host path to write changed SVGs to
+	outHostPath string,
+	// The minimum delay between export polls, in milliseconds
+	// +optional
+	// +default=1000
+	debounceMs int,
+) error {
+
+	const port = 9000
+
+	resolvedEntry, err := watchEntry(ctx, dir, entry)
+	if err != nil {
+		return err
+	}
+
+	svc := m.watchContainer(dir, resolvedEntry, port, "0.0.0.0").AsService()
+
+	started, err := svc.Start(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start watch service: %w", err)
+	}
+	defer started.Stop(ctx)
+
+	ticker := time.NewTicker(time.Duration(debounceMs) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			preview := dag.Container().
+				From("alpine").
+				WithServiceBinding("d2-watch", started).
+				WithExec([]string{"wget", "-q", "-O", "preview.svg", fmt.Sprintf("http://d2-watch:%d/", port)}).
+				File("preview.svg")
+
+			if _, err := preview.Export(ctx, outHostPath); err != nil {
+				return fmt.Errorf("failed to export watched output: %w", err)
+			}
+		}
+	}
 }
 
 // renders the D2 file or directory to the given format format.
 func (m *D2) Render() (*dagger.Directory, error) {
 
 	if m.File != nil {
-		return renderFile(m.File, m.Format, m.Args), nil
+		return renderFile(m.File, m.Format, m.sharedArgs()), nil
 	}
 
 	if m.Dir != nil {
-		return renderDir(m.Dir, m.Format, m.Args), nil
+		return renderDir(m.Dir, m.Format, m.sharedArgs()), nil
 	}
 
 	return nil, errors.New("no file or directory provided")
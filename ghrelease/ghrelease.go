@@ -0,0 +1,252 @@
+// Package ghrelease is a small shared library for discovering GitHub releases from Dagger
+// modules. It exists so that modules which need "the latest release of some repo" (WingetRelease,
+// ParadoxClausewitzSave, ...) don't each reinvent it with ad-hoc curl/sed pipelines against
+// redirect headers. It talks to the GitHub REST API directly over net/http, optionally
+// authenticated with a token to relieve the unauthenticated rate limit.
+//
+// This is a plain Go package, not a Dagger module: each module's generated `internal/dagger`
+// package defines its own distinct Client/Secret types, so a shared helper can't accept those
+// types across module boundaries. Callers resolve a *dagger.Secret to a string with
+// secret.Plaintext(ctx) before calling in here.
+package ghrelease
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// release mirrors the subset of the GitHub releases API response this package needs.
+type release struct {
+	TagName    string  `json:"tag_name"`
+	Prerelease bool    `json:"prerelease"`
+	Assets     []asset `json:"assets"`
+}
+
+type asset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// LatestTag returns the tag name of the latest non-prerelease release of owner/repo. token is a
+// plain-text GitHub token used to relieve the unauthenticated rate limit; pass "" to go unauthenticated.
+func LatestTag(ctx context.Context, token, owner, repo string) (string, error) {
+	rel, err := fetchRelease(ctx, token, owner, repo, "latest")
+	if err != nil {
+		return "", err
+	}
+	return rel.TagName, nil
+}
+
+// MatchingTag returns the newest tag of owner/repo satisfying the given semver constraint
+// (e.g. ">=v1.2.0", "^v2.0.0"), considering prereleases only when includePrereleases is true.
+func MatchingTag(ctx context.Context, token, owner, repo, constraint string, includePrereleases bool) (string, error) {
+	releases, err := fetchReleases(ctx, token, owner, repo)
+	if err != nil {
+		return "", err
+	}
+
+	cmp, err := parseConstraint(constraint)
+	if err != nil {
+		return "", err
+	}
+
+	var candidates []semver
+	for _, rel := range releases {
+		if rel.Prerelease && !includePrereleases {
+			continue
+		}
+		v, ok := parseSemver(rel.TagName)
+		if !ok {
+			continue
+		}
+		if cmp(v) {
+			candidates = append(candidates, v)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no release of %s/%s matches constraint %q", owner, repo, constraint)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].less(candidates[j]) })
+	return candidates[len(candidates)-1].tag, nil
+}
+
+// AssetURL returns the browser_download_url of the asset of owner/repo's `tag` release whose
+// name matches the given glob-style pattern (e.g. "*_linux_amd64.tar.gz").
+func AssetURL(ctx context.Context, token, owner, repo, tag, pattern string) (string, error) {
+	rel, err := fetchRelease(ctx, token, owner, repo, "tags/"+tag)
+	if err != nil {
+		return "", err
+	}
+
+	for _, a := range rel.Assets {
+		if ok, _ := filepath.Match(pattern, a.Name); ok {
+			return a.BrowserDownloadURL, nil
+		}
+	}
+
+	return "", fmt.Errorf("no asset of %s/%s@%s matches pattern %q", owner, repo, tag, pattern)
+}
+
+// IsTagPresentInPath reports whether the GitHub code search API finds any file under `path`
+// (relative to the repo root, e.g. a manifest directory in a package registry fork). Callers put
+// the tag in path (e.g. ".../Dagger/Cli/0.13.0") to avoid re-submitting a package manifest PR for
+// a release that's already published downstream.
+func IsTagPresentInPath(ctx context.Context, token, owner, repo, path string) (bool, error) {
+	query := fmt.Sprintf("repo:%s/%s path:%s", owner, repo, path)
+	url := fmt.Sprintf("https://api.github.com/search/code?q=%s", urlQueryEscape(query))
+
+	contents, err := get(ctx, token, url)
+	if err != nil {
+		return false, err
+	}
+
+	var result struct {
+		TotalCount int `json:"total_count"`
+	}
+	if err := json.Unmarshal([]byte(contents), &result); err != nil {
+		return false, fmt.Errorf("failed to parse GitHub code search response: %w", err)
+	}
+
+	return result.TotalCount > 0, nil
+}
+
+func fetchRelease(ctx context.Context, token, owner, repo, ref string) (release, error) {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/%s", owner, repo, ref)
+
+	contents, err := get(ctx, token, url)
+	if err != nil {
+		return release{}, err
+	}
+
+	var rel release
+	if err := json.Unmarshal([]byte(contents), &rel); err != nil {
+		return release{}, fmt.Errorf("failed to parse GitHub release response: %w", err)
+	}
+
+	return rel, nil
+}
+
+func fetchReleases(ctx context.Context, token, owner, repo string) ([]release, error) {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases?per_page=100", owner, repo)
+
+	contents, err := get(ctx, token, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var releases []release
+	if err := json.Unmarshal([]byte(contents), &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub releases response: %w", err)
+	}
+
+	return releases, nil
+}
+
+// get performs a GET against the GitHub API, authenticated when token is non-empty, and returns
+// the response body.
+func get(ctx context.Context, token, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build GitHub API request to %s: %w", url, err)
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("GitHub API request to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read GitHub API response from %s: %w", url, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GitHub API request to %s failed with status %d: %s", url, resp.StatusCode, body)
+	}
+
+	return string(body), nil
+}
+
+func urlQueryEscape(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, " ", "+"), ":", "%3A")
+}
+
+// semver is a minimal parsed "vX.Y.Z" release tag.
+type semver struct {
+	tag   string
+	major int
+	minor int
+	patch int
+}
+
+var semverPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)`)
+
+func parseSemver(tag string) (semver, bool) {
+	m := semverPattern.FindStringSubmatch(tag)
+	if m == nil {
+		return semver{}, false
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	patch, _ := strconv.Atoi(m[3])
+	return semver{tag: tag, major: major, minor: minor, patch: patch}, true
+}
+
+func (v semver) less(other semver) bool {
+	if v.major != other.major {
+		return v.major < other.major
+	}
+	if v.minor != other.minor {
+		return v.minor < other.minor
+	}
+	return v.patch < other.patch
+}
+
+// parseConstraint supports the small subset of semver range syntax this package needs:
+// ">=vX.Y.Z", ">vX.Y.Z", "^vX.Y.Z" (same major), and a bare "vX.Y.Z" for exact match.
+func parseConstraint(constraint string) (func(semver) bool, error) {
+	constraint = strings.TrimSpace(constraint)
+
+	switch {
+	case strings.HasPrefix(constraint, ">="):
+		v, ok := parseSemver(strings.TrimPrefix(constraint, ">="))
+		if !ok {
+			return nil, fmt.Errorf("invalid constraint: %q", constraint)
+		}
+		return func(c semver) bool { return !c.less(v) }, nil
+	case strings.HasPrefix(constraint, ">"):
+		v, ok := parseSemver(strings.TrimPrefix(constraint, ">"))
+		if !ok {
+			return nil, fmt.Errorf("invalid constraint: %q", constraint)
+		}
+		return func(c semver) bool { return v.less(c) }, nil
+	case strings.HasPrefix(constraint, "^"):
+		v, ok := parseSemver(strings.TrimPrefix(constraint, "^"))
+		if !ok {
+			return nil, fmt.Errorf("invalid constraint: %q", constraint)
+		}
+		return func(c semver) bool { return c.major == v.major && !c.less(v) }, nil
+	default:
+		v, ok := parseSemver(constraint)
+		if !ok {
+			return nil, fmt.Errorf("invalid constraint: %q", constraint)
+		}
+		return func(c semver) bool { return c == v }, nil
+	}
+}
@@ -4,27 +4,47 @@ package main
 
 import (
 	"context"
+	"dagger/heroes-decode/internal/dagger"
+	"encoding/json"
 	"fmt"
-	"heroes-decode/internal/dagger"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
+
+	"golang.org/x/sync/errgroup"
 )
 
 type HeroesDecode struct {
 }
 
-// The heroes decode command
-func (m *HeroesDecode) Decode(
-	ctx context.Context,
-	// +optional
-	// The replay file to decode
-	file *dagger.File,
-	// +optional
-	// Additional arguments to pass to the decoder
-	args []string,
+// ReplayInfo is the structured result of DecodeJSON/DecodeBatch, parsed out of HeroesDecode's
+// `--show-player-*` stdout.
+type ReplayInfo struct {
+	// The battleground the replay was played on
+	Map string `json:"map"`
+	// The Heroes of the Storm build the replay was recorded with
+	GameVersion string `json:"game_version"`
+	// The match length, e.g. "19m32s"
+	Duration string `json:"duration"`
+	// The two teams, each a slice of players
+	Teams [][]Player `json:"teams"`
+}
 
-) (*dagger.Container, error) {
+// Player is a single participant in a decoded replay.
+type Player struct {
+	// The player's battletag
+	Name string `json:"name"`
+	// The hero the player played
+	Hero string `json:"hero"`
+	// The talents picked, in pick order
+	Talents []string `json:"talents"`
+	// The player's matchmaking rating for this hero, if available
+	MMR int `json:"mmr"`
+}
 
+// app builds the HeroesDecode binary from the pinned upstream tag.
+func app() *dagger.Container {
 	repo := dag.Git("https://github.com/HeroesToolChest/HeroesDecode.git")
 	dir := repo.Tag("v1.4.0").Tree()
 
@@ -34,32 +54,223 @@ func (m *HeroesDecode) Decode(
 		WithDirectory("/app", dir.Directory("HeroesDecode")).
 		WithExec([]string{"dotnet", "publish", "-c", "Release"})
 
-	app := dag.Container().
+	return dag.Container().
 		From("mcr.microsoft.com/dotnet/runtime:8.0").
 		WithWorkdir("/app").
 		WithDirectory("/app", build.Directory("/app/bin/Release/net8.0/publish")).
 		WithEntrypoint([]string{"./HeroesDecode"})
+}
 
-	cmd := []string{}
+// replayPath mounts the given replay file under a timestamped name and returns the container
+// plus the path it was mounted at, so concurrent decodes of different replays don't collide.
+func replayPath(container *dagger.Container, file *dagger.File) (*dagger.Container, string) {
+	name := fmt.Sprintf("%s.StormReplay", strings.ReplaceAll(time.Now().Format(time.RFC3339Nano), ":", "_"))
+	path := fmt.Sprintf("/app/%s", name)
+	return container.WithFile(path, file), path
+}
 
-	if file != nil {
+// The heroes decode command
+func (m *HeroesDecode) Decode(
+	ctx context.Context,
+	// +optional
+	// The replay file to decode
+	file *dagger.File,
+	// +optional
+	// Additional arguments to pass to the decoder
+	args []string,
 
-		replayName := fmt.Sprintf("%s.StormReplay", strings.ReplaceAll(time.Now().Format(time.RFC3339Nano), ":", "_"))
-		replayPath := fmt.Sprintf("/app/%s", replayName)
-		replay := []string{"--replay-path", replayPath}
+) (*dagger.Container, error) {
 
-		app, _ = app.WithFile(replayPath, file).Sync(ctx)
+	container := app()
+	cmd := []string{}
 
-		cmd = append(cmd, replay...)
+	if file != nil {
+		var path string
+		container, path = replayPath(container, file)
+		cmd = append(cmd, "--replay-path", path)
 	}
 
 	if args != nil {
 		cmd = append(cmd, args...)
 	}
 
-	return app.
+	return container.
 		WithExec(cmd, dagger.ContainerWithExecOpts{
 			UseEntrypoint: true,
 		}).
 		Sync(ctx)
 }
+
+// DecodeJSON decodes a single replay and returns a structured ReplayInfo as Dagger JSON.
+func (m *HeroesDecode) DecodeJSON(ctx context.Context, file *dagger.File) (dagger.JSON, error) {
+
+	container, path := replayPath(app(), file)
+
+	out, err := container.
+		WithExec([]string{
+			"--replay-path", path,
+			"--show-player-talents",
+			"--show-player-heroes",
+			"--show-player-mmr",
+		}, dagger.ContainerWithExecOpts{UseEntrypoint: true}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode replay: %w", err)
+	}
+
+	info := parseReplayInfo(out)
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal replay info: %w", err)
+	}
+
+	return dagger.JSON(data), nil
+}
+
+// DecodeBatch decodes every .StormReplay at the top level of dir concurrently, writing one JSON
+// file per replay plus an index.json summarising all of them. Subdirectories are not searched.
+func (m *HeroesDecode) DecodeBatch(ctx context.Context, dir *dagger.Directory) (*dagger.Directory, error) {
+
+	entries, err := dir.Entries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replay directory: %w", err)
+	}
+
+	var replays []string
+	for _, entry := range entries {
+		if strings.HasSuffix(entry, ".StormReplay") {
+			replays = append(replays, entry)
+		}
+	}
+
+	infos := make([]ReplayInfo, len(replays))
+
+	g, gctx := errgroup.WithContext(ctx)
+	for i, entry := range replays {
+		i, entry := i, entry
+		g.Go(func() error {
+			container, path := replayPath(app(), dir.File(entry))
+
+			out, err := container.
+				WithExec([]string{
+					"--replay-path", path,
+					"--show-player-talents",
+					"--show-player-heroes",
+					"--show-player-mmr",
+				}, dagger.ContainerWithExecOpts{UseEntrypoint: true}).
+				Stdout(gctx)
+			if err != nil {
+				return fmt.Errorf("failed to decode %s: %w", entry, err)
+			}
+
+			infos[i] = parseReplayInfo(out)
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	result := dag.Directory()
+	index := make(map[string]ReplayInfo, len(replays))
+
+	for i, entry := range replays {
+		info := infos[i]
+		index[entry] = info
+
+		data, err := json.Marshal(info)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal replay info for %s: %w", entry, err)
+		}
+
+		jsonName := strings.TrimSuffix(entry, ".StormReplay") + ".json"
+		result = result.WithNewFile(jsonName, string(data))
+	}
+
+	indexData, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal replay index: %w", err)
+	}
+
+	return result.WithNewFile("index.json", string(indexData)), nil
+}
+
+var (
+	mapPattern      = regexp.MustCompile(`(?mi)^\s*map:\s*(.+)$`)
+	versionPattern  = regexp.MustCompile(`(?mi)^\s*(?:game )?version:\s*(.+)$`)
+	durationPattern = regexp.MustCompile(`(?mi)^\s*(?:game )?length:\s*(.+)$`)
+	playerPattern   = regexp.MustCompile(`(?mi)^\s*team\s*(\d+)\s*[:\-]\s*(.+?)\s*\((.+?)\)(?:\s*,\s*mmr\s*(\d+))?\s*$`)
+	talentPattern   = regexp.MustCompile(`(?mi)^\s*\d+(?::\d+)?\s*[-:]\s*(.+)$`)
+)
+
+// parseReplayInfo extracts the fields DecodeJSON/DecodeBatch care about out of HeroesDecode's
+// human-readable stdout. With --show-player-talents, each player's talent picks are printed as
+// an indented block of "<level> - <talent name>" lines directly below that player's "Team N:"
+// line; parseReplayInfo collects the block following each match and folds it into that player's
+// Talents slice.
+func parseReplayInfo(stdout string) ReplayInfo {
+	info := ReplayInfo{
+		Teams: make([][]Player, 2),
+	}
+
+	if m := mapPattern.FindStringSubmatch(stdout); m != nil {
+		info.Map = strings.TrimSpace(m[1])
+	}
+
+	if m := versionPattern.FindStringSubmatch(stdout); m != nil {
+		info.GameVersion = strings.TrimSpace(m[1])
+	}
+
+	if m := durationPattern.FindStringSubmatch(stdout); m != nil {
+		info.Duration = strings.TrimSpace(m[1])
+	}
+
+	playerLines := playerPattern.FindAllStringSubmatchIndex(stdout, -1)
+
+	for pi, idx := range playerLines {
+		m := make([]string, len(idx)/2)
+		for i := range m {
+			if idx[2*i] >= 0 {
+				m[i] = stdout[idx[2*i]:idx[2*i+1]]
+			}
+		}
+
+		team, err := strconv.Atoi(m[1])
+		if err != nil || team < 1 || team > 2 {
+			continue
+		}
+
+		player := Player{
+			Name: strings.TrimSpace(m[2]),
+			Hero: strings.TrimSpace(m[3]),
+		}
+
+		if m[4] != "" {
+			if mmr, err := strconv.Atoi(m[4]); err == nil {
+				player.MMR = mmr
+			}
+		}
+
+		blockEnd := len(stdout)
+		if pi+1 < len(playerLines) {
+			blockEnd = playerLines[pi+1][0]
+		}
+		player.Talents = parseTalents(stdout[idx[1]:blockEnd])
+
+		info.Teams[team-1] = append(info.Teams[team-1], player)
+	}
+
+	return info
+}
+
+// parseTalents pulls the "<level> - <talent name>" lines out of the stdout block following a
+// player's "Team N:" line, up to the next player's line (or end of output).
+func parseTalents(block string) []string {
+	var talents []string
+	for _, m := range talentPattern.FindAllStringSubmatch(block, -1) {
+		talents = append(talents, strings.TrimSpace(m[1]))
+	}
+	return talents
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"dagger/kiota/internal/dagger"
 	"fmt"
+
+	"golang.org/x/sync/errgroup"
 )
 
 type Kiota struct {
@@ -15,6 +17,8 @@ type Kiota struct {
 	Container *dagger.Container
 	// Common parameters to be used in all Kiota commands
 	Parameters []string
+	// The output path most recently passed to Generate, Download or Plugin, used by Output/Export/File
+	OutputPath string
 }
 
 // New Kiota module
@@ -59,6 +63,7 @@ func New(
 		LogLevel:   *LogLevel,
 		Parameters: []string{"--log-level", string(*LogLevel)},
 		Container:  container,
+		OutputPath: "/app/output",
 	}
 
 	return kiota
@@ -87,6 +92,23 @@ var (
 	TypeScript Language = "typescript"
 )
 
+// Provider is a description registry/auth provider accepted by `kiota login`/`kiota logout`.
+type Provider string
+
+var (
+	Github           Provider = "github"
+	GithubDeviceCode Provider = "githubdevicecode"
+)
+
+// PluginType selects the flavour of API Plugin/Copilot manifest `kiota plugin generate` emits.
+type PluginType string
+
+var (
+	ApiPlugin   PluginType = "apiplugin"
+	ApiManifest PluginType = "apimanifest"
+	OpenAI      PluginType = "openai"
+)
+
 type LogLevel string
 
 var (
@@ -178,6 +200,62 @@ func (m *Kiota) Search(
 	return m.Container.WithExec([]string{"search", searchTerm}, dagger.ContainerWithExecOpts{UseEntrypoint: true}).Stdout(context.Background())
 }
 
+// Login authenticates against the given description registry provider via `kiota login`, so
+// Search/Download can resolve private or rate-limited descriptions. tokenSecret is passed through
+// WithSecretVariable so it never gets baked into a container layer.
+func (m *Kiota) Login(
+	provider Provider,
+	// +optional
+	tokenSecret *dagger.Secret,
+) *Kiota {
+	container := m.Container
+	if tokenSecret != nil {
+		container = container.WithSecretVariable("GITHUB_TOKEN", tokenSecret)
+	}
+
+	m.Container = container.WithExec([]string{"login", string(provider)}, dagger.ContainerWithExecOpts{UseEntrypoint: true})
+	return m
+}
+
+// Logout clears credentials previously established by Login for the given provider.
+func (m *Kiota) Logout(provider Provider) *Kiota {
+	m.Container = m.Container.WithExec([]string{"logout", string(provider)}, dagger.ContainerWithExecOpts{UseEntrypoint: true})
+	return m
+}
+
+// Download fetches a description from the API descriptions registry via `kiota download`,
+// resolved by searchKey (as returned by Search). The downloaded description is retrievable
+// through Output() afterwards.
+func (m *Kiota) Download(
+	// The registry search key identifying the description to download, e.g. "github::github/rest#v1"
+	searchKey string,
+	// +optional
+	version *string,
+	// +optional
+	outputPath *string,
+	// +optional
+	cleanOutput *bool,
+) *Kiota {
+
+	parameters := []string{"--search-key", searchKey}
+
+	if version != nil {
+		parameters = append(parameters, "--version", *version)
+	}
+
+	if outputPath != nil {
+		parameters = append(parameters, "--output", *outputPath)
+		m.OutputPath = *outputPath
+	}
+
+	if cleanOutput != nil && *cleanOutput {
+		parameters = append(parameters, "--clean-output")
+	}
+
+	m.Container = m.Container.WithExec(append([]string{"download"}, append(m.Parameters, parameters...)...), dagger.ContainerWithExecOpts{UseEntrypoint: true})
+	return m
+}
+
 func (m *Kiota) Show(
 	openApi *string,
 	clearCache *bool,
@@ -237,6 +315,12 @@ func (m *Kiota) Show(
 func (m *Kiota) Generate(
 	// +optional
 	openApi *string,
+	// A local OpenAPI spec to mount into the container and generate from, instead of a URL
+	// +optional
+	sources *dagger.Directory,
+	// The path of the spec within sources to pass as --openapi, relative to the mounted directory
+	// +optional
+	sourcesPath *string,
 	// +optional
 	language *Language,
 	// +optional
@@ -262,9 +346,18 @@ func (m *Kiota) Generate(
 
 	parameters := make([]string, 0)
 
-	if openApi != nil {
-		parameters = append(parameters, "--openapi")
-		parameters = append(parameters, *openApi)
+	if sources != nil {
+		mountPath := "/app/input"
+		m.Container = m.Container.WithMountedDirectory(mountPath, sources)
+
+		specPath := mountPath
+		if sourcesPath != nil {
+			specPath = fmt.Sprintf("%s/%s", mountPath, *sourcesPath)
+		}
+
+		parameters = append(parameters, "--openapi", specPath)
+	} else if openApi != nil {
+		parameters = append(parameters, "--openapi", *openApi)
 	}
 
 	if language != nil {
@@ -321,12 +414,329 @@ func (m *Kiota) Generate(
 	if outputPath != nil {
 		parameters = append(parameters, "--output")
 		parameters = append(parameters, *outputPath)
+		m.OutputPath = *outputPath
 	}
 
 	m.Container = m.Container.WithExec(append([]string{"generate"}, append(m.Parameters, parameters...)...), dagger.ContainerWithExecOpts{UseEntrypoint: true})
 	return m
 }
 
+// Output returns the directory at the most recent --output path passed to Generate, Download or
+// Plugin (/app/output if none was given).
+func (m *Kiota) Output() *dagger.Directory {
+	return m.Container.Directory(m.OutputPath)
+}
+
+// Export writes Output() to the given path on the host.
+func (m *Kiota) Export(ctx context.Context, hostPath string) (bool, error) {
+	return m.Output().Export(ctx, hostPath)
+}
+
+// File returns a single file out of Output() by path relative to it.
+func (m *Kiota) File(ctx context.Context, relPath string) (*dagger.File, error) {
+	file := m.Output().File(relPath)
+	if _, err := file.Sync(ctx); err != nil {
+		return nil, fmt.Errorf("failed to read %s from kiota output: %w", relPath, err)
+	}
+	return file, nil
+}
+
+// Plugin generates an API Plugin/Copilot manifest via `kiota plugin generate`, distinct from
+// SDK client generation. A caller building agent tools from an OpenAPI spec can produce both a
+// typed SDK (Generate) and a plugin descriptor (Plugin) in the same pipeline.
+func (m *Kiota) Plugin(
+	// The OpenAPI spec URL or path
+	openApi string,
+	// The plugin manifest flavour to emit
+	pluginType PluginType,
+	// The path to write the plugin manifest to
+	outputPath string,
+	// The name to register the plugin under
+	pluginName string,
+	// +optional
+	includePath *string,
+	// +optional
+	excludePath *string,
+	// The authentication type the plugin manifest declares, e.g. "none", "oauth" or "apikey"
+	// +optional
+	authType *string,
+	// The auth reference ID to embed in the manifest, required when authType needs one
+	// +optional
+	authRefId *string,
+) *Kiota {
+
+	parameters := []string{
+		"--openapi", openApi,
+		"--type", string(pluginType),
+		"--output", outputPath,
+		"--plugin-name", pluginName,
+	}
+
+	if includePath != nil {
+		parameters = append(parameters, "--include-path", *includePath)
+	}
+
+	if excludePath != nil {
+		parameters = append(parameters, "--exclude-path", *excludePath)
+	}
+
+	if authType != nil {
+		parameters = append(parameters, "--auth-type", *authType)
+	}
+
+	if authRefId != nil {
+		parameters = append(parameters, "--auth-reference-id", *authRefId)
+	}
+
+	m.Container = m.Container.WithExec(append([]string{"plugin", "generate"}, append(m.Parameters, parameters...)...), dagger.ContainerWithExecOpts{UseEntrypoint: true})
+	m.OutputPath = outputPath
+	return m
+}
+
+// KiotaWorkspace drives a checked-in kiota workspace (apimanifest.json/kiota-config.json),
+// letting a caller re-generate its clients and plugins deterministically instead of rebuilding
+// a CLI argument list on every Dagger invocation.
+type KiotaWorkspace struct {
+	// The Kiota container, working directory set to the mounted workspace
+	Container *dagger.Container
+}
+
+// Workspace mounts dir as a kiota workspace, initializing one with `kiota workspace init` if it
+// doesn't already contain an apimanifest.json.
+func (m *Kiota) Workspace(ctx context.Context, dir *dagger.Directory) (*KiotaWorkspace, error) {
+	entries, err := dir.Entries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace directory: %w", err)
+	}
+
+	initialized := false
+	for _, entry := range entries {
+		if entry == "apimanifest.json" {
+			initialized = true
+			break
+		}
+	}
+
+	container := m.Container.
+		WithMountedDirectory("/app/workspace", dir).
+		WithWorkdir("/app/workspace")
+
+	if !initialized {
+		container = container.WithExec([]string{"workspace", "init"}, dagger.ContainerWithExecOpts{UseEntrypoint: true})
+	}
+
+	return &KiotaWorkspace{Container: container}, nil
+}
+
+// ClientAdd registers a new client in the workspace via `kiota client add`.
+func (w *KiotaWorkspace) ClientAdd(
+	// The name to register the client under
+	name string,
+	// The OpenAPI spec URL or path
+	openApi string,
+	// The language to generate the client in
+	language Language,
+	// +optional
+	outputPath *string,
+	// +optional
+	className *string,
+	// +optional
+	includePath *string,
+	// +optional
+	excludePath *string,
+	// +optional
+	structuredMimeTypes []string,
+) *KiotaWorkspace {
+
+	args := []string{"client", "add", name, "--openapi", openApi, "--language", string(language)}
+
+	if outputPath != nil {
+		args = append(args, "--output", *outputPath)
+	}
+	if className != nil {
+		args = append(args, "--class-name", *className)
+	}
+	if includePath != nil {
+		args = append(args, "--include-path", *includePath)
+	}
+	if excludePath != nil {
+		args = append(args, "--exclude-path", *excludePath)
+	}
+	for _, mimeType := range structuredMimeTypes {
+		args = append(args, "--structured-mime-types", mimeType)
+	}
+
+	w.Container = w.Container.WithExec(args, dagger.ContainerWithExecOpts{UseEntrypoint: true})
+	return w
+}
+
+// PluginAdd registers a new API Plugin/Copilot manifest in the workspace via `kiota plugin add`.
+func (w *KiotaWorkspace) PluginAdd(
+	// The name to register the plugin under
+	name string,
+	// The OpenAPI spec URL or path
+	openApi string,
+	// The plugin type
+	pluginType PluginType,
+	// +optional
+	outputPath *string,
+) *KiotaWorkspace {
+
+	args := []string{"plugin", "add", name, "--openapi", openApi, "--type", string(pluginType)}
+
+	if outputPath != nil {
+		args = append(args, "--output", *outputPath)
+	}
+
+	w.Container = w.Container.WithExec(args, dagger.ContainerWithExecOpts{UseEntrypoint: true})
+	return w
+}
+
+// Generate runs `kiota client generate`, regenerating the named client, or every client in the
+// workspace if clientName is nil.
+func (w *KiotaWorkspace) Generate(
+	// +optional
+	clientName *string,
+) *KiotaWorkspace {
+
+	args := []string{"client", "generate"}
+
+	if clientName != nil {
+		args = append(args, "--client-name", *clientName)
+	} else {
+		args = append(args, "--all")
+	}
+
+	w.Container = w.Container.WithExec(args, dagger.ContainerWithExecOpts{UseEntrypoint: true})
+	return w
+}
+
+// Migrate runs `kiota workspace migrate`, converting a pre-workspace kiota-lock.json layout into
+// the current apimanifest.json-based workspace format.
+func (w *KiotaWorkspace) Migrate() *KiotaWorkspace {
+	w.Container = w.Container.WithExec([]string{"workspace", "migrate"}, dagger.ContainerWithExecOpts{UseEntrypoint: true})
+	return w
+}
+
+// Output returns the workspace directory, including the generated clients, apimanifest.json and
+// kiota-lock.json.
+func (w *KiotaWorkspace) Output() *dagger.Directory {
+	return w.Container.Directory(".")
+}
+
+// Spec is one OpenAPI document to generate clients for, as part of a GenerateBatch call.
+type Spec struct {
+	// A short name identifying this spec, used as the top-level key in GenerateBatch's merged output, e.g. "energy"
+	Name string
+	// The OpenAPI spec URL, passed to kiota as --openapi
+	URL string
+	// The per-language clients to generate for this spec
+	Settings []Settings
+}
+
+// Settings are the per-language generation options for one Spec in a GenerateBatch call.
+type Settings struct {
+	// The language to generate a client for
+	Language Language
+	// The namespace/package the generated client is placed under
+	Namespace string
+	// The class name of the generated client's root client class
+	ClassName string
+	// The path this client's output is written to within the merged result, under "<spec.Name>/<language>" by default
+	// +optional
+	OutputPath string
+	// Paths to limit generation to
+	// +optional
+	IncludePath string
+	// Paths to exclude from generation
+	// +optional
+	ExcludePath string
+	// MIME types to generate strongly-typed models for
+	// +optional
+	StructuredMimeTypes []string
+}
+
+// GenerateBatch generates every (spec, language) pair declared across specs in a single call,
+// running the underlying `kiota generate` invocations concurrently and merging their outputs into
+// one directory keyed by "<spec.Name>/<language>" (or by Settings.OutputPath when set). This lets a caller produce, in one Dagger call,
+// all clients for several specs and languages instead of hand-rolling the orchestration themselves.
+func (m *Kiota) GenerateBatch(ctx context.Context, specs []Spec) (*dagger.Directory, error) {
+
+	type job struct {
+		key     string
+		spec    Spec
+		setting Settings
+	}
+
+	var jobs []job
+	for _, spec := range specs {
+		for _, setting := range spec.Settings {
+			jobs = append(jobs, job{
+				key:     fmt.Sprintf("%s/%s", spec.Name, setting.Language),
+				spec:    spec,
+				setting: setting,
+			})
+		}
+	}
+
+	dirs := make([]*dagger.Directory, len(jobs))
+
+	g, gctx := errgroup.WithContext(ctx)
+	for i, j := range jobs {
+		i, j := i, j
+		g.Go(func() error {
+			outputPath := j.setting.OutputPath
+			if outputPath == "" {
+				outputPath = fmt.Sprintf("/app/output/%s", j.key)
+			}
+
+			openApi := j.spec.URL
+			language := j.setting.Language
+
+			var namespaceName, className, includePath, excludePath *string
+			if j.setting.Namespace != "" {
+				namespaceName = &j.setting.Namespace
+			}
+			if j.setting.ClassName != "" {
+				className = &j.setting.ClassName
+			}
+			if j.setting.IncludePath != "" {
+				includePath = &j.setting.IncludePath
+			}
+			if j.setting.ExcludePath != "" {
+				excludePath = &j.setting.ExcludePath
+			}
+
+			k := &Kiota{Container: m.Container, Parameters: append([]string{}, m.Parameters...)}
+			k = k.WithParameters(nil, excludePath, includePath, nil, nil, nil, nil, nil, nil, nil)
+			k.Generate(&openApi, nil, nil, &language, nil, &outputPath, className, nil, nil, nil, nil, namespaceName, j.setting.StructuredMimeTypes, nil)
+
+			ctr, err := k.Container.Sync(gctx)
+			if err != nil {
+				return fmt.Errorf("generate %s failed: %w", j.key, err)
+			}
+
+			dirs[i] = ctr.Directory(outputPath)
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	result := dag.Directory()
+	for i, j := range jobs {
+		dest := j.key
+		if j.setting.OutputPath != "" {
+			dest = j.setting.OutputPath
+		}
+		result = result.WithDirectory(dest, dirs[i])
+	}
+
+	return result, nil
+}
+
 func (m *Kiota) Info() *Kiota {
 	m.Container = m.Container.WithExec(append([]string{"info"}, m.Parameters...), dagger.ContainerWithExecOpts{UseEntrypoint: true})
 	return m
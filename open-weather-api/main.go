@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"open-weather-api/internal/dagger"
 	"strconv"
+	"strings"
+	"time"
 
 	owm "github.com/briandowns/openweathermap"
 )
@@ -17,8 +19,18 @@ type OpenWeatherApi struct {
 	Unit string
 	// The 2-letter ISO code for the language to use (en, es, etc.)
 	Lang string
+	// The provider to fetch weather data from ("openweathermap" or "open-meteo")
+	Provider string
 	// The weather data returned by the API call
 	Result Weather
+	// The hourly forecast returned by UseForecast
+	Hourly []HourlyForecast
+	// The daily forecast returned by UseDaily
+	Daily []DailyForecast
+	// The alerts returned by UseAlerts
+	Alerts []Alert
+	// The air quality data returned by UseAirPollution
+	AirQuality AirQuality
 	// The elements to include in the JSON response
 	Fields []string
 }
@@ -38,9 +50,55 @@ type Weather struct {
 	Icon string `json:"icon"`
 }
 
+// HourlyForecast is a single hour of the result of UseForecast
+type HourlyForecast struct {
+	// The ISO8601 timestamp this forecast applies to
+	Time string `json:"time"`
+	// The forecast temperature in the requested unit
+	Temp string `json:"temp"`
+	// The description of the weather (e.g. "clear sky")
+	Description string `json:"description"`
+}
+
+// DailyForecast is a single day of the result of UseDaily
+type DailyForecast struct {
+	// The ISO8601 date this forecast applies to
+	Date string `json:"date"`
+	// The minimum temperature in the requested unit
+	MinTemp string `json:"min_temp"`
+	// The maximum temperature in the requested unit
+	MaxTemp string `json:"max_temp"`
+	// The description of the weather (e.g. "light rain")
+	Description string `json:"description"`
+}
+
+// Alert is a single government weather alert, part of the result of UseAlerts
+type Alert struct {
+	// The name of the entity that issued the alert
+	Sender string `json:"sender"`
+	// The short name of the alert event (e.g. "Flood Warning")
+	Event string `json:"event"`
+	// The full alert text
+	Description string `json:"description"`
+}
+
+// AirQuality is the result of UseAirPollution
+type AirQuality struct {
+	// The Air Quality Index: OpenWeatherMap's 1 (good) to 5 (very poor) scale, or the US EPA 0-500 scale from open-meteo
+	AQI int `json:"aqi"`
+	// Pollutant concentrations in μg/m³, keyed by pollutant (e.g. "pm2_5", "o3", "no2")
+	Pollutants map[string]float64 `json:"pollutants"`
+}
+
+const (
+	OpenWeatherMap = "openweathermap"
+	OpenMeteo      = "open-meteo"
+)
+
 // creates a new instance of the OpenWeatherApi
 func New(
-	// The apiKey to use for the OpenWeatherMap API
+	// The apiKey to use for the OpenWeatherMap API, not required for the forecast, alert and air pollution calls with the open-meteo provider
+	// +optional
 	apiKey *dagger.Secret,
 	// The unit to use (C, F, or K)
 	// +default="C"
@@ -49,12 +107,23 @@ func New(
 	// +default="en"
 	lang string) *OpenWeatherApi {
 	return &OpenWeatherApi{
-		ApiKey: apiKey,
-		Unit:   unit,
-		Lang:   lang,
+		ApiKey:   apiKey,
+		Unit:     unit,
+		Lang:     lang,
+		Provider: OpenWeatherMap,
 	}
 }
 
+// selects which provider UseForecast, UseDaily, UseAlerts and UseAirPollution fetch data from;
+// UseCoordinates and UseLocation always use OpenWeatherMap
+func (m *OpenWeatherApi) WithProvider(
+	// "openweathermap" (requires ApiKey) or "open-meteo" (keyless)
+	provider string,
+) *OpenWeatherApi {
+	m.Provider = provider
+	return m
+}
+
 // the fields to include in the response when returning a formatted response
 func (m *OpenWeatherApi) WithFields(
 	// The fields to include in the response
@@ -139,6 +208,43 @@ func (m *OpenWeatherApi) AsJson() (dagger.JSON, error) {
 	return dagger.JSON(result), err
 }
 
+// formats the hourly forecast, daily forecast and any alerts currently loaded into a compact
+// multi-day summary suitable for feeding into an LLM step in a Dagger pipeline
+func (m *OpenWeatherApi) AsPrompt() string {
+	var b strings.Builder
+
+	if m.Result.Summary != "" {
+		b.WriteString(fmt.Sprintf("Current: %s\n", m.Result.Summary))
+	}
+
+	if len(m.Hourly) > 0 {
+		b.WriteString("Hourly forecast:\n")
+		for _, h := range m.Hourly {
+			b.WriteString(fmt.Sprintf("- %s: %s, %s%s\n", h.Time, h.Description, h.Temp, displayUnit(m.Unit)))
+		}
+	}
+
+	if len(m.Daily) > 0 {
+		b.WriteString("Daily forecast:\n")
+		for _, d := range m.Daily {
+			b.WriteString(fmt.Sprintf("- %s: %s, %s%s to %s%s\n", d.Date, d.Description, d.MinTemp, displayUnit(m.Unit), d.MaxTemp, displayUnit(m.Unit)))
+		}
+	}
+
+	if len(m.Alerts) > 0 {
+		b.WriteString("Alerts:\n")
+		for _, a := range m.Alerts {
+			b.WriteString(fmt.Sprintf("- [%s] %s: %s\n", a.Sender, a.Event, a.Description))
+		}
+	}
+
+	if m.AirQuality.AQI != 0 {
+		b.WriteString(fmt.Sprintf("Air quality index: %d\n", m.AirQuality.AQI))
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
 // retrieves the current weather for the given latitude and longitude
 func (m *OpenWeatherApi) UseCoordinates(
 	// The latitude
@@ -195,6 +301,191 @@ func (m *OpenWeatherApi) UseLocation(
 	return m, nil
 }
 
+// retrieves an hourly forecast for the given coordinates, populating Hourly with up to `hours` entries
+func (m *OpenWeatherApi) UseForecast(
+	ctx context.Context,
+	// The latitude
+	lat string,
+	// The longitude
+	lon string,
+	// The number of hourly entries to keep
+	// +default=24
+	hours int,
+) (*OpenWeatherApi, error) {
+
+	if m.Provider == OpenMeteo {
+		hourly, err := m.openMeteoHourly(ctx, lat, lon, hours)
+		if err != nil {
+			return nil, err
+		}
+		m.Hourly = hourly
+		return m, nil
+	}
+
+	oc, err := m.newOneCall(lat, lon)
+	if err != nil {
+		return nil, err
+	}
+
+	hourly := make([]HourlyForecast, 0, hours)
+	for i, h := range oc.Hourly {
+		if i >= hours {
+			break
+		}
+		hourly = append(hourly, HourlyForecast{
+			Time:        time.Unix(int64(h.Dt), 0).UTC().Format(time.RFC3339),
+			Temp:        fmt.Sprintf("%g", h.Temp),
+			Description: weatherDescription(h.Weather),
+		})
+	}
+
+	m.Hourly = hourly
+	return m, nil
+}
+
+// retrieves a daily forecast for the given coordinates, populating Daily with up to `days` entries
+func (m *OpenWeatherApi) UseDaily(
+	ctx context.Context,
+	// The latitude
+	lat string,
+	// The longitude
+	lon string,
+	// The number of daily entries to keep
+	// +default=7
+	days int,
+) (*OpenWeatherApi, error) {
+
+	if m.Provider == OpenMeteo {
+		daily, err := m.openMeteoDaily(ctx, lat, lon, days)
+		if err != nil {
+			return nil, err
+		}
+		m.Daily = daily
+		return m, nil
+	}
+
+	oc, err := m.newOneCall(lat, lon)
+	if err != nil {
+		return nil, err
+	}
+
+	daily := make([]DailyForecast, 0, days)
+	for i, d := range oc.Daily {
+		if i >= days {
+			break
+		}
+		daily = append(daily, DailyForecast{
+			Date:        time.Unix(int64(d.Dt), 0).UTC().Format(time.RFC3339),
+			MinTemp:     fmt.Sprintf("%g", d.Temp.Min),
+			MaxTemp:     fmt.Sprintf("%g", d.Temp.Max),
+			Description: weatherDescription(d.Weather),
+		})
+	}
+
+	m.Daily = daily
+	return m, nil
+}
+
+// retrieves any active government weather alerts for the given coordinates
+func (m *OpenWeatherApi) UseAlerts(
+	// The latitude
+	lat string,
+	// The longitude
+	lon string,
+) (*OpenWeatherApi, error) {
+
+	if m.Provider == OpenMeteo {
+		// open-meteo does not publish government weather alerts
+		m.Alerts = nil
+		return m, nil
+	}
+
+	oc, err := m.newOneCall(lat, lon)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := make([]Alert, 0, len(oc.Alerts))
+	for _, a := range oc.Alerts {
+		alerts = append(alerts, Alert{
+			Sender:      a.SenderName,
+			Event:       a.Event,
+			Description: a.Description,
+		})
+	}
+
+	m.Alerts = alerts
+	return m, nil
+}
+
+// retrieves current air quality index and pollutant concentrations for the given coordinates
+func (m *OpenWeatherApi) UseAirPollution(
+	ctx context.Context,
+	// The latitude
+	lat string,
+	// The longitude
+	lon string,
+) (*OpenWeatherApi, error) {
+
+	if m.Provider == OpenMeteo {
+		aqi, err := m.openMeteoAirPollution(ctx, lat, lon)
+		if err != nil {
+			return nil, err
+		}
+		m.AirQuality = aqi
+		return m, nil
+	}
+
+	apiKey, err := m.ApiKey.Plaintext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pollution, err := owm.NewPollution(apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	latf, err := strconv.ParseFloat(lat, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	lonf, err := strconv.ParseFloat(lon, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pollution.PollutionByParams(&owm.PollutionParameters{
+		Location: owm.Coordinates{Latitude: latf, Longitude: lonf},
+		Datetime: "current",
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(pollution.List) == 0 {
+		return nil, fmt.Errorf("no air pollution data returned for %s,%s", lat, lon)
+	}
+
+	entry := pollution.List[0]
+
+	m.AirQuality = AirQuality{
+		AQI: entry.Main.Aqi,
+		Pollutants: map[string]float64{
+			"co":    entry.Components.CO,
+			"no":    entry.Components.NO,
+			"no2":   entry.Components.NO2,
+			"o3":    entry.Components.O3,
+			"so2":   entry.Components.SO2,
+			"pm2_5": entry.Components.PM2_5,
+			"pm10":  entry.Components.PM10,
+			"nh3":   entry.Components.NH3,
+		},
+	}
+
+	return m, nil
+}
+
 func (m *OpenWeatherApi) newCurrent() (*owm.CurrentWeatherData, error) {
 	apiKey, err := m.ApiKey.Plaintext(context.Background())
 	if err != nil {
@@ -203,6 +494,34 @@ func (m *OpenWeatherApi) newCurrent() (*owm.CurrentWeatherData, error) {
 	return owm.NewCurrent(m.Unit, m.Lang, apiKey)
 }
 
+func (m *OpenWeatherApi) newOneCall(lat, lon string) (*owm.OneCallData, error) {
+	apiKey, err := m.ApiKey.Plaintext(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	latf, err := strconv.ParseFloat(lat, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	lonf, err := strconv.ParseFloat(lon, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	oc, err := owm.NewOneCall(m.Unit, m.Lang, apiKey, []string{"minutely"})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := oc.OneCallByCoordinates(&owm.Coordinates{Latitude: latf, Longitude: lonf}); err != nil {
+		return nil, err
+	}
+
+	return oc, nil
+}
+
 func (m *OpenWeatherApi) getWeather(current *owm.CurrentWeatherData) (Weather, error) {
 
 	unit := displayUnit(current.Unit)
@@ -265,6 +584,13 @@ func (m *OpenWeatherApi) getIcon(current *owm.CurrentWeatherData) string {
 	}
 }
 
+func weatherDescription(conditions []owm.Weather) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return conditions[0].Description
+}
+
 func displayUnit(unit string) string {
 	switch unit {
 	case "metric", "C":
@@ -277,3 +603,153 @@ func displayUnit(unit string) string {
 		return unit
 	}
 }
+
+// openMeteoResponse is the subset of the open-meteo forecast response this module consumes
+type openMeteoResponse struct {
+	Hourly struct {
+		Time          []string  `json:"time"`
+		Temperature2m []float64 `json:"temperature_2m"`
+		WeatherCode   []int     `json:"weather_code"`
+	} `json:"hourly"`
+	Daily struct {
+		Time             []string  `json:"time"`
+		Temperature2mMin []float64 `json:"temperature_2m_min"`
+		Temperature2mMax []float64 `json:"temperature_2m_max"`
+		WeatherCode      []int     `json:"weather_code"`
+	} `json:"daily"`
+}
+
+func (m *OpenWeatherApi) fetchOpenMeteo(ctx context.Context, lat, lon string, params string) (*openMeteoResponse, error) {
+	if m.Unit == "F" || m.Unit == "imperial" {
+		params += "&temperature_unit=fahrenheit"
+	}
+
+	url := fmt.Sprintf("https://api.open-meteo.com/v1/forecast?latitude=%s&longitude=%s&%s", lat, lon, params)
+
+	contents, err := dag.HTTP(url).Contents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch open-meteo forecast: %w", err)
+	}
+
+	var parsed openMeteoResponse
+	if err := json.Unmarshal([]byte(contents), &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse open-meteo forecast: %w", err)
+	}
+
+	// open-meteo only offers celsius and fahrenheit, so kelvin is converted from celsius here
+	if m.Unit == "K" || m.Unit == "kelvin" {
+		for _, temps := range [][]float64{parsed.Hourly.Temperature2m, parsed.Daily.Temperature2mMin, parsed.Daily.Temperature2mMax} {
+			for i := range temps {
+				temps[i] += 273.15
+			}
+		}
+	}
+
+	return &parsed, nil
+}
+
+func (m *OpenWeatherApi) openMeteoHourly(ctx context.Context, lat, lon string, hours int) ([]HourlyForecast, error) {
+	parsed, err := m.fetchOpenMeteo(ctx, lat, lon, "hourly=temperature_2m,weather_code")
+	if err != nil {
+		return nil, err
+	}
+
+	hourly := make([]HourlyForecast, 0, hours)
+	for i := range parsed.Hourly.Time {
+		if i >= hours {
+			break
+		}
+		hourly = append(hourly, HourlyForecast{
+			Time:        parsed.Hourly.Time[i],
+			Temp:        fmt.Sprintf("%g", parsed.Hourly.Temperature2m[i]),
+			Description: weatherCodeDescription(parsed.Hourly.WeatherCode[i]),
+		})
+	}
+
+	return hourly, nil
+}
+
+func (m *OpenWeatherApi) openMeteoDaily(ctx context.Context, lat, lon string, days int) ([]DailyForecast, error) {
+	parsed, err := m.fetchOpenMeteo(ctx, lat, lon, fmt.Sprintf("daily=temperature_2m_min,temperature_2m_max,weather_code&forecast_days=%d", days))
+	if err != nil {
+		return nil, err
+	}
+
+	daily := make([]DailyForecast, 0, days)
+	for i := range parsed.Daily.Time {
+		if i >= days {
+			break
+		}
+		daily = append(daily, DailyForecast{
+			Date:        parsed.Daily.Time[i],
+			MinTemp:     fmt.Sprintf("%g", parsed.Daily.Temperature2mMin[i]),
+			MaxTemp:     fmt.Sprintf("%g", parsed.Daily.Temperature2mMax[i]),
+			Description: weatherCodeDescription(parsed.Daily.WeatherCode[i]),
+		})
+	}
+
+	return daily, nil
+}
+
+func (m *OpenWeatherApi) openMeteoAirPollution(ctx context.Context, lat, lon string) (AirQuality, error) {
+	url := fmt.Sprintf("https://air-quality-api.open-meteo.com/v1/air-quality?latitude=%s&longitude=%s&current=us_aqi,pm2_5,pm10,ozone,nitrogen_dioxide,sulphur_dioxide,carbon_monoxide", lat, lon)
+
+	contents, err := dag.HTTP(url).Contents(ctx)
+	if err != nil {
+		return AirQuality{}, fmt.Errorf("failed to fetch open-meteo air quality: %w", err)
+	}
+
+	var parsed struct {
+		Current struct {
+			USAqi           float64 `json:"us_aqi"`
+			PM2_5           float64 `json:"pm2_5"`
+			PM10            float64 `json:"pm10"`
+			Ozone           float64 `json:"ozone"`
+			NitrogenDioxide float64 `json:"nitrogen_dioxide"`
+			SulphurDioxide  float64 `json:"sulphur_dioxide"`
+			CarbonMonoxide  float64 `json:"carbon_monoxide"`
+		} `json:"current"`
+	}
+
+	if err := json.Unmarshal([]byte(contents), &parsed); err != nil {
+		return AirQuality{}, fmt.Errorf("failed to parse open-meteo air quality: %w", err)
+	}
+
+	return AirQuality{
+		AQI: int(parsed.Current.USAqi),
+		Pollutants: map[string]float64{
+			"pm2_5": parsed.Current.PM2_5,
+			"pm10":  parsed.Current.PM10,
+			"o3":    parsed.Current.Ozone,
+			"no2":   parsed.Current.NitrogenDioxide,
+			"so2":   parsed.Current.SulphurDioxide,
+			"co":    parsed.Current.CarbonMonoxide,
+		},
+	}, nil
+}
+
+// weatherCodeDescription maps an open-meteo WMO weather code to a short human-readable description
+func weatherCodeDescription(code int) string {
+	switch {
+	case code == 0:
+		return "clear sky"
+	case code <= 3:
+		return "partly cloudy"
+	case code == 45 || code == 48:
+		return "fog"
+	case code >= 51 && code <= 57:
+		return "drizzle"
+	case code >= 61 && code <= 67:
+		return "rain"
+	case code >= 71 && code <= 77:
+		return "snow"
+	case code >= 80 && code <= 82:
+		return "rain showers"
+	case code >= 85 && code <= 86:
+		return "snow showers"
+	case code >= 95:
+		return "thunderstorm"
+	default:
+		return "unknown"
+	}
+}
@@ -10,13 +10,147 @@ import (
 	"fmt"
 	"runtime"
 	"strings"
+
+	"github.com/Khan/genqlient/graphql"
+	"github.com/pjmagee/daggerverse/ghrelease"
 )
 
 type ParadoxClausewitzSave struct {
+	// Extractor plugins registered via WithPlugin, run in addition to the built-ins
+	Plugins []PluginModule
+}
+
+// Game identifies a supported Clausewitz title, auto-detected from the melted save header.
+type Game string
+
+const (
+	Stellaris Game = "stellaris"
+	CK3       Game = "ck3"
+	HOI4      Game = "hoi4"
+	EU4       Game = "eu4"
+)
+
+// Plugin extracts a slice of structured data out of an already-parsed save.
+// Built-in plugins implement this directly; plugins registered with WithPlugin
+// are adapted onto it through PluginModule.
+type Plugin interface {
+	Name() string
+	SupportedGames() []Game
+	Extract(ctx context.Context, parsedJSON *dagger.File) (*dagger.Directory, error)
+}
+
+// PluginModule adapts an external Dagger module, registered via WithPlugin, to the Plugin interface.
+// The module is expected to expose an `extract` function taking the parsed save as a `--parsed-json`
+// file argument and returning a directory, mirroring the signature of the built-in plugins.
+type PluginModule struct {
+	// The registered module
+	Module *dagger.Module
+	// The name this plugin is registered under
+	PluginName string
+}
+
+func (p PluginModule) Name() string {
+	return p.PluginName
+}
+
+func (p PluginModule) SupportedGames() []Game {
+	// External plugins declare support for every game, so Extract runs them whatever
+	// game was detected.
+	return []Game{Stellaris, CK3, HOI4, EU4}
+}
+
+// Extract serves the registered module into the current session's schema and dynamically calls its
+// `extract` function over GraphQL, passing the parsed save as the `parsedJson` file argument. A
+// typed call isn't possible here since PluginModule wraps a module picked at runtime via WithPlugin,
+// so there's no generated binding for its schema to call through, unlike the built-in plugins.
+func (p PluginModule) Extract(ctx context.Context, parsedJSON *dagger.File) (*dagger.Directory, error) {
+	if _, err := p.Module.Serve(ctx); err != nil {
+		return nil, fmt.Errorf("failed to serve plugin module %s: %w", p.PluginName, err)
+	}
+
+	name, err := p.Module.Name(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve plugin module %s: %w", p.PluginName, err)
+	}
+
+	fileID, err := parsedJSON.ID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve parsed save file for plugin %s: %w", p.PluginName, err)
+	}
+
+	var response struct {
+		Result struct {
+			ID string `json:"id"`
+		} `json:"result"`
+	}
+
+	query := fmt.Sprintf(
+		`query Extract($parsedJson: FileID!) { result: %s { extract(parsedJson: $parsedJson) { id } } }`,
+		schemaFieldName(name),
+	)
+
+	req := &graphql.Request{
+		Query:     query,
+		Variables: map[string]any{"parsedJson": fileID},
+	}
+
+	if err := dag.GraphQLClient().MakeRequest(ctx, req, &graphql.Response{Data: &response}); err != nil {
+		return nil, fmt.Errorf("plugin %s extract call failed: %w", p.PluginName, err)
+	}
+
+	return dag.LoadDirectoryFromID(dagger.DirectoryID(response.Result.ID)), nil
+}
+
+// schemaFieldName converts a dash-cased module name (e.g. "my-plugin") into the camelCase field
+// name (e.g. "myPlugin") Dagger exposes that module's main object constructor under once served.
+func schemaFieldName(moduleName string) string {
+	parts := strings.Split(moduleName, "-")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] == "" {
+			continue
+		}
+		parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+	}
+	return strings.Join(parts, "")
+}
+
+// builtinPlugins returns the plugins shipped with this module: empires, wars, economy and map-render.
+func builtinPlugins() []Plugin {
+	return []Plugin{
+		empiresPlugin{},
+		warsPlugin{},
+		economyPlugin{},
+		mapRenderPlugin{},
+	}
+}
+
+// WithPlugin registers an extractor implemented by another Dagger module, in addition to the built-ins.
+func (m *ParadoxClausewitzSave) WithPlugin(
+	// The Dagger module implementing the extractor
+	mod *dagger.Module,
+	// The name to register the plugin under
+	name string,
+) *ParadoxClausewitzSave {
+	m.Plugins = append(m.Plugins, PluginModule{Module: mod, PluginName: name})
+	return m
+}
+
+// tokenPlaintext resolves an optional GitHub token secret to a plain string, since ghrelease is a
+// plain Go package and can't accept this module's generated *dagger.Secret type directly.
+func tokenPlaintext(ctx context.Context, token *dagger.Secret) (string, error) {
+	if token == nil {
+		return "", nil
+	}
+	return token.Plaintext(ctx)
 }
 
 // downloads and returns the latest release binary for the current platform
-func (m *ParadoxClausewitzSave) GetBinary() (*dagger.File, error) {
+func (m *ParadoxClausewitzSave) GetBinary(
+	ctx context.Context,
+	// A GitHub token to authenticate with for rate-limit relief
+	// +optional
+	githubToken *dagger.Secret,
+) (*dagger.File, error) {
 
 	platform := runtime.GOOS
 	arch := runtime.GOARCH
@@ -39,34 +173,99 @@ func (m *ParadoxClausewitzSave) GetBinary() (*dagger.File, error) {
 		extension = "zip"
 	}
 
-	latestReleaseURL := "https://github.com/pjmagee/paradox-clausewitz-save/releases/latest"
-
-	container := dag.Container().
-		From("ubuntu:latest").
-		WithExec([]string{"apt-get", "update"}).
-		WithExec([]string{"apt-get", "install", "-y", "curl"})
-
-	cmd := []string{
-		"sh", "-c",
-		"curl -s -I -L " + latestReleaseURL + " | grep -i 'location:' | tail -n 1 | sed 's/.*\\/v\\([^/]*\\).*/\\1/' | tr -d '\\r\\n'",
+	token, err := tokenPlaintext(ctx, githubToken)
+	if err != nil {
+		return nil, err
 	}
 
-	version, err := container.WithExec(cmd).Stdout(context.Background())
+	tag, err := ghrelease.LatestTag(ctx, token, "pjmagee", "paradox-clausewitz-save")
 	if err != nil {
 		return nil, fmt.Errorf("failed to get latest version: %w", err)
 	}
 
-	version = strings.TrimSpace(version)
+	version := strings.TrimPrefix(tag, "v")
 	if version == "" {
 		version = "1.0.0"
 	}
 
-	filename := fmt.Sprintf("mageesoft-pdx-ce-sav_%s_%s_%s.%s", version, mappedPlatform, mappedArch, extension)
-	url := fmt.Sprintf("https://github.com/pjmagee/paradox-clausewitz-save/releases/download/v%s/%s", version, filename)
+	pattern := fmt.Sprintf("mageesoft-pdx-ce-sav_%s_%s_%s.%s", version, mappedPlatform, mappedArch, extension)
+	url, err := ghrelease.AssetURL(ctx, token, "pjmagee", "paradox-clausewitz-save", tag, pattern)
+	if err != nil {
+		return nil, err
+	}
 
 	return dag.HTTP(url), nil
 }
 
+// detectGame inspects the melted save header to determine which Clausewitz title produced it.
+func detectGame(header string) Game {
+	header = strings.ToLower(header)
+	switch {
+	case strings.Contains(header, "stellaris"):
+		return Stellaris
+	case strings.Contains(header, "ck3") || strings.Contains(header, "crusader"):
+		return CK3
+	case strings.Contains(header, "hoi4") || strings.Contains(header, "hearts of iron"):
+		return HOI4
+	case strings.Contains(header, "eu4") || strings.Contains(header, "europa universalis"):
+		return EU4
+	default:
+		return ""
+	}
+}
+
+// parse melts the save file once and returns the parsed JSON file alongside the detected game.
+func (m *ParadoxClausewitzSave) parse(ctx context.Context, saveFile *dagger.File) (*dagger.File, Game, error) {
+
+	binary, err := m.GetBinary(ctx, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	container := dag.Container().
+		From("ubuntu:latest").
+		WithExec([]string{"apt-get", "update"}).
+		WithExec([]string{"apt-get", "install", "-y", "tar", "unzip", "file"})
+
+	container = container.WithExec([]string{"mkdir", "-p", "/app"})
+	container = container.WithMountedFile("/tmp/binary", binary)
+
+	binaryName, err := binary.Name(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get binary name: %w", err)
+	}
+
+	if strings.HasSuffix(binaryName, ".zip") {
+		container = container.WithExec([]string{"unzip", "/tmp/binary", "-d", "/app"})
+	} else {
+		container = container.WithExec([]string{"tar", "-xzf", "/tmp/binary", "-C", "/app"})
+	}
+
+	container = container.
+		WithExec([]string{"chmod", "+x", "/app/mageesoft-pdx-ce-sav"}).
+		WithWorkdir("/app").
+		WithExec([]string{"apt-get", "install", "-y", "libc6", "libstdc++6", "libicu-dev"}).
+		WithEnvVariable("DOTNET_SYSTEM_GLOBALIZATION_INVARIANT", "1").
+		WithMountedFile("/tmp/save", saveFile)
+
+	parsed := container.
+		WithExec([]string{"./mageesoft-pdx-ce-sav", "-s", "/tmp/save", "--json", "/app/parsed.json"}).
+		File("/app/parsed.json")
+
+	header, err := parsed.Contents(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read parsed save: %w", err)
+	}
+
+	headerLen := len(header)
+	if headerLen > 2048 {
+		headerLen = 2048
+	}
+	game := detectGame(header[:headerLen])
+
+	return parsed, game, nil
+}
+
 // processes a Paradox Clausewitz save file with the specified arguments
 func (m *ParadoxClausewitzSave) Process(
 	ctx context.Context,
@@ -75,7 +274,7 @@ func (m *ParadoxClausewitzSave) Process(
 	// +optional
 	args []string) (string, error) {
 
-	binary, err := m.GetBinary()
+	binary, err := m.GetBinary(ctx, nil)
 
 	if err != nil {
 		return "", err
@@ -132,3 +331,107 @@ func (m *ParadoxClausewitzSave) Process(
 	// Return the output
 	return result.Stdout(ctx)
 }
+
+// Extract parses the save once, then fans out to the built-in plugins and any plugins registered
+// via WithPlugin, merging their outputs into a single result directory keyed by plugin name.
+func (m *ParadoxClausewitzSave) Extract(
+	ctx context.Context,
+	saveFile *dagger.File,
+) (*dagger.Directory, error) {
+
+	parsedJSON, game, err := m.parse(ctx, saveFile)
+	if err != nil {
+		return nil, err
+	}
+
+	result := dag.Directory()
+
+	plugins := append(builtinPlugins(), pluginsAsInterfaces(m.Plugins)...)
+
+	for _, plugin := range plugins {
+		if game != "" && !supports(plugin, game) {
+			continue
+		}
+
+		extracted, err := plugin.Extract(ctx, parsedJSON)
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s failed: %w", plugin.Name(), err)
+		}
+
+		result = result.WithDirectory(plugin.Name(), extracted)
+	}
+
+	return result, nil
+}
+
+func pluginsAsInterfaces(modules []PluginModule) []Plugin {
+	plugins := make([]Plugin, 0, len(modules))
+	for _, module := range modules {
+		plugins = append(plugins, module)
+	}
+	return plugins
+}
+
+func supports(plugin Plugin, game Game) bool {
+	for _, g := range plugin.SupportedGames() {
+		if g == game {
+			return true
+		}
+	}
+	return false
+}
+
+// empiresPlugin extracts per-empire/per-realm summaries (rulers, population, tech for Stellaris;
+// titles and dynasties for CK3, etc).
+type empiresPlugin struct{}
+
+func (empiresPlugin) Name() string           { return "empires" }
+func (empiresPlugin) SupportedGames() []Game { return []Game{Stellaris, CK3, HOI4, EU4} }
+func (empiresPlugin) Extract(ctx context.Context, parsedJSON *dagger.File) (*dagger.Directory, error) {
+	return extractWithJq(parsedJSON, "empires.json", ".country // .title // .nations // {}")
+}
+
+// warsPlugin extracts active and historical wars/conflicts.
+type warsPlugin struct{}
+
+func (warsPlugin) Name() string           { return "wars" }
+func (warsPlugin) SupportedGames() []Game { return []Game{Stellaris, CK3, HOI4, EU4} }
+func (warsPlugin) Extract(ctx context.Context, parsedJSON *dagger.File) (*dagger.Directory, error) {
+	return extractWithJq(parsedJSON, "wars.json", ".previous_war // .active_war // .war // {}")
+}
+
+// economyPlugin extracts budget, trade and resource data.
+type economyPlugin struct{}
+
+func (economyPlugin) Name() string           { return "economy" }
+func (economyPlugin) SupportedGames() []Game { return []Game{Stellaris, CK3, HOI4, EU4} }
+func (economyPlugin) Extract(ctx context.Context, parsedJSON *dagger.File) (*dagger.Directory, error) {
+	return extractWithJq(parsedJSON, "economy.json", ".budget // .economy // .trade // {}")
+}
+
+// mapRenderPlugin writes the parsed save's systems/provinces section to map-data.json, alongside a
+// map.svg that is an empty SVG document; no map is drawn from the data.
+type mapRenderPlugin struct{}
+
+func (mapRenderPlugin) Name() string           { return "map-render" }
+func (mapRenderPlugin) SupportedGames() []Game { return []Game{Stellaris, CK3, HOI4, EU4} }
+func (p mapRenderPlugin) Extract(ctx context.Context, parsedJSON *dagger.File) (*dagger.Directory, error) {
+	return dag.Container().
+		From("alpine").
+		WithExec([]string{"apk", "add", "jq"}).
+		WithMountedFile("/tmp/parsed.json", parsedJSON).
+		WithWorkdir("/tmp").
+		WithExec([]string{"sh", "-c", "jq '.galactic_object // .provinces // {}' parsed.json > map-data.json"}).
+		WithExec([]string{"sh", "-c", "echo '<svg xmlns=\"http://www.w3.org/2000/svg\"/>' > map.svg"}).
+		Directory("/tmp")
+}
+
+func extractWithJq(parsedJSON *dagger.File, outputName string, jqFilter string) (*dagger.Directory, error) {
+	return dag.Container().
+		From("alpine").
+		WithExec([]string{"apk", "add", "jq"}).
+		WithMountedFile("/tmp/parsed.json", parsedJSON).
+		WithWorkdir("/tmp").
+		WithExec([]string{"sh", "-c", fmt.Sprintf("jq '%s' parsed.json > %s", jqFilter, outputName)}).
+		Directory("/tmp"), nil
+}
@@ -2,55 +2,75 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"dagger/winget-release/internal/dagger"
 	"fmt"
-	"net/http"
 	"strings"
+
+	"github.com/pjmagee/daggerverse/ghrelease"
 )
 
 type WingetRelease struct {
 }
 
-type Release struct {
-	TagName string `json:"tag_name"`
-	HtmlUrl string `json:"html_url"`
-}
-
-// New method to check if release is already published
-func (m *WingetRelease) IsReleasePublished(version string) (bool, error) {
-	url := fmt.Sprintf("https://github.com/microsoft/winget-pkgs/tree/master/manifests/d/Dagger/Cli/%s", version)
-	resp, err := http.Get(url)
-	if err != nil {
-		return false, fmt.Errorf("failed to check winget repository: %w", err)
+// tokenPlaintext resolves an optional GitHub token secret to a plain string, since ghrelease is a
+// plain Go package and can't accept this module's generated *dagger.Secret type directly.
+func tokenPlaintext(ctx context.Context, token *dagger.Secret) (string, error) {
+	if token == nil {
+		return "", nil
 	}
-	defer resp.Body.Close()
-
-	// If status is 200, the release exists
-	return resp.StatusCode == http.StatusOK, nil
+	return token.Plaintext(ctx)
 }
 
-func (m *WingetRelease) GetLatestRelease() (string, error) {
-	resp := dag.HTTP("https://api.github.com/repos/dagger/dagger/releases/latest")
-
-	contents, err := resp.Contents(context.Background())
+// IsReleasePublished reports whether version has already been submitted to winget-pkgs, by
+// searching for a manifest under its Dagger.Cli path rather than scraping the GitHub web UI.
+func (m *WingetRelease) IsReleasePublished(
+	ctx context.Context,
+	// A GitHub token to authenticate with for rate-limit relief
+	// +optional
+	githubToken *dagger.Secret,
+	version string,
+) (bool, error) {
+	token, err := tokenPlaintext(ctx, githubToken)
 	if err != nil {
-		return contents, err
+		return false, err
 	}
+	path := fmt.Sprintf("manifests/d/Dagger/Cli/%s", version)
+	return ghrelease.IsTagPresentInPath(ctx, token, "microsoft", "winget-pkgs", path)
+}
 
-	release := Release{}
-	err = json.Unmarshal([]byte(contents), &release)
+// GetLatestRelease returns the newest dagger/dagger tag, or the newest tag satisfying
+// versionConstraint (e.g. ">=v0.13.0") when one is given.
+func (m *WingetRelease) GetLatestRelease(
+	ctx context.Context,
+	// A GitHub token to authenticate with for rate-limit relief
+	// +optional
+	githubToken *dagger.Secret,
+	// A semver constraint to match instead of taking the newest release, e.g. ">=v0.13.0"
+	// +optional
+	versionConstraint string,
+	// Whether a matched release may be a prerelease
+	// +optional
+	includePrereleases bool,
+) (string, error) {
+	token, err := tokenPlaintext(ctx, githubToken)
 	if err != nil {
 		return "", err
 	}
-
-	return release.TagName, nil
+	if versionConstraint != "" {
+		return ghrelease.MatchingTag(ctx, token, "dagger", "dagger", versionConstraint, includePrereleases)
+	}
+	return ghrelease.LatestTag(ctx, token, "dagger", "dagger")
 }
 
 func (m *WingetRelease) CreateWingetCommand(
+	ctx context.Context,
+	// A GitHub token to authenticate with for rate-limit relief
+	// +optional
+	githubToken *dagger.Secret,
 	// The version of the release
 	// +optional
 	tag string,
-	// The token to use for the wingetcreate command
+	// Whether to pass --token $env:WINGETCREATE_TOKEN, for a container with that secret mounted
 	// +optional
 	tokenPlaceholder bool,
 	// Whether to add the --submit
@@ -60,7 +80,7 @@ func (m *WingetRelease) CreateWingetCommand(
 ) (string, error) {
 
 	if tag == "" {
-		latest, err := m.GetLatestRelease()
+		latest, err := m.GetLatestRelease(ctx, githubToken, "", false)
 		if err != nil {
 			return "", err
 		}
@@ -69,9 +89,7 @@ func (m *WingetRelease) CreateWingetCommand(
 
 	version := strings.TrimPrefix(tag, "v")
 
-	// Check if release is already published
-	published, err := m.IsReleasePublished(version)
-
+	published, err := m.IsReleasePublished(ctx, githubToken, version)
 	if err != nil {
 		return "", fmt.Errorf("failed to check if release is published: %w", err)
 	}
@@ -100,3 +118,29 @@ func (m *WingetRelease) CreateWingetCommand(
 
 	return cmd, nil
 }
+
+// SubmitPR runs wingetcreate update --submit in a Windows servercore container, actually opening
+// the winget-pkgs PR rather than just printing the command a human would have to run themselves.
+func (m *WingetRelease) SubmitPR(
+	ctx context.Context,
+	// The GitHub token wingetcreate uses to fork winget-pkgs and open the PR
+	token *dagger.Secret,
+	// The version of the release to submit
+	// +optional
+	tag string,
+) (string, error) {
+
+	cmd, err := m.CreateWingetCommand(ctx, token, tag, true, true)
+	if err != nil {
+		return "", err
+	}
+
+	container := dag.Container(dagger.ContainerOpts{Platform: "windows/amd64"}).
+		From("mcr.microsoft.com/windows/servercore:ltsc2022").
+		WithSecretVariable("WINGETCREATE_TOKEN", token).
+		WithExec([]string{"powershell", "-Command",
+			"Invoke-WebRequest -Uri https://aka.ms/wingetcreate/latest -OutFile wingetcreate.exe"}).
+		WithExec([]string{"powershell", "-Command", cmd})
+
+	return container.Stdout(ctx)
+}
@@ -2,18 +2,54 @@
 package main
 
 import (
+	"context"
 	"dagger/youtube-dl/internal/dagger"
+	"encoding/json"
 	"fmt"
+	"strings"
+)
+
+// Backend selects which downloader binary the module installs.
+type Backend string
+
+const (
+	// The original, unmaintained youtube-dl project
+	YoutubeDlBackend Backend = "youtube-dl"
+	// The actively maintained yt-dlp fork
+	YtDlpBackend Backend = "yt-dlp"
+)
+
+// Channel selects which yt-dlp release track to pull the binary from.
+type Channel string
+
+const (
+	// yt-dlp/yt-dlp, tagged stable releases
+	Stable Channel = "stable"
+	// yt-dlp/yt-dlp-nightly-builds, built daily from master
+	Nightly Channel = "nightly"
+	// yt-dlp/yt-dlp-master-builds, built on every push to master
+	Master Channel = "master"
 )
 
 func New(
-	// The nightly version of youtube-dl to use
+	// Which downloader to install
+	// +optional
+	// +default="yt-dlp"
+	backend Backend,
+	// Which yt-dlp release channel to pull from (ignored for the youtube-dl backend)
+	// +optional
+	// +default="stable"
+	channel Channel,
+	// The youtube-dl nightly version to use (ignored for the yt-dlp backend)
 	// +optional
 	// +default="2025.01.01"
 	version string,
 ) *YoutubeDl {
 	return &YoutubeDl{
-		Cli:     Cli(version),
+		Backend: backend,
+		Channel: channel,
+		Version: version,
+		Cli:     Cli(backend, channel, version),
 		Options: make([]string, 0),
 		Urls:    make([]string, 0),
 	}
@@ -24,14 +60,18 @@ var (
 )
 
 type YoutubeDl struct {
-	// The youtube-dl cli container
+	// The youtube-dl/yt-dlp cli container
 	Cli *dagger.Container
-	// youtubne-dl [OPTIONS]
+	// youtube-dl [OPTIONS]
 	Options []string
 	// youtube-dl [OPTIONS] URL [URL ...]
 	Urls []string
 	// The version of youtube-dl to use
 	Version string
+	// The downloader backend in use
+	Backend Backend
+	// The yt-dlp release channel in use
+	Channel Channel
 }
 
 func Ubuntu() *dagger.Container {
@@ -40,18 +80,54 @@ func Ubuntu() *dagger.Container {
 		From("ubuntu")
 }
 
-func Cli(version string) *dagger.Container {
+// ytDlpRepo maps a release channel to the GitHub repository that publishes it.
+func ytDlpRepo(channel Channel) string {
+	switch channel {
+	case Nightly:
+		return "yt-dlp/yt-dlp-nightly-builds"
+	case Master:
+		return "yt-dlp/yt-dlp-master-builds"
+	default:
+		return "yt-dlp/yt-dlp"
+	}
+}
+
+func Cli(backend Backend, channel Channel, version string) *dagger.Container {
 
 	cache := dag.CacheVolume("youtube-dl")
 
-	return Ubuntu().
+	container := Ubuntu().
 		WithWorkdir("/app").
 		WithExec([]string{"sh", "-c", "apt-get update && apt-get install -y wget python3.12"}).
-		WithExec([]string{"sh", "-c", "ln -s /usr/bin/python3.12 /usr/bin/python"}).
-		WithExec([]string{"sh", "-c", fmt.Sprintf("wget https://github.com/ytdl-org/ytdl-nightly/releases/download/%s/youtube-dl -O /app/youtube-dl", version)}).
-		WithExec([]string{"sh", "-c", "chmod a+rx /app/youtube-dl"}).
+		WithExec([]string{"sh", "-c", "ln -s /usr/bin/python3.12 /usr/bin/python"})
+
+	var entrypoint string
+
+	if backend == YtDlpBackend {
+		entrypoint = "./yt-dlp"
+
+		// The container built above is always linux (From("ubuntu")) regardless of the host OS/arch
+		// running this pipeline, so the asset name is resolved against `uname -m` inside the
+		// container rather than the host's runtime.GOOS/GOARCH.
+		script := fmt.Sprintf(`set -eu
+case "$(uname -m)" in
+  aarch64|arm64) asset=yt-dlp_linux_aarch64 ;;
+  *) asset=yt-dlp_linux ;;
+esac
+wget "https://github.com/%s/releases/latest/download/$asset" -O %s
+`, ytDlpRepo(channel), entrypoint)
+
+		container = container.WithExec([]string{"sh", "-c", script})
+	} else {
+		downloadURL := fmt.Sprintf("https://github.com/ytdl-org/ytdl-nightly/releases/download/%s/youtube-dl", version)
+		entrypoint = "./youtube-dl"
+		container = container.WithExec([]string{"sh", "-c", fmt.Sprintf("wget %s -O %s", downloadURL, entrypoint)})
+	}
+
+	return container.
+		WithExec([]string{"sh", "-c", fmt.Sprintf("chmod a+rx %s", entrypoint)}).
 		WithMountedCache("~/.cache/youtube-dl", cache).
-		WithEntrypoint([]string{"./youtube-dl"})
+		WithEntrypoint([]string{entrypoint})
 }
 
 // The options to pass for youtube-dl [OPTIONS] ...
@@ -71,6 +147,140 @@ func (m *YoutubeDl) WithUrls(
 	return m
 }
 
+// Restricts the download to the given format selector, e.g. "bestvideo+bestaudio/best"
+func (m *YoutubeDl) WithFormat(
+	format string,
+) *YoutubeDl {
+	return m.WithOptions([]string{"--format", format})
+}
+
+// Extracts audio only, transcoding to the given codec and quality
+func (m *YoutubeDl) WithAudioOnly(
+	// The audio codec to transcode to, e.g. "mp3", "m4a", "opus"
+	codec string,
+	// The ffmpeg audio quality, 0 (best) to 9 (worst)
+	quality string,
+) *YoutubeDl {
+	return m.WithOptions([]string{
+		"--extract-audio",
+		"--audio-format", codec,
+		"--audio-quality", quality,
+	})
+}
+
+// Downloads subtitles for the given languages, optionally embedding them into the video
+func (m *YoutubeDl) WithSubtitles(
+	// The subtitle languages to fetch, e.g. []string{"en", "fr"}
+	langs []string,
+	// Whether to embed the subtitles into the output file rather than writing them alongside it
+	embed bool,
+) *YoutubeDl {
+	options := []string{"--write-subs", "--sub-langs", strings.Join(langs, ",")}
+	if embed {
+		options = append(options, "--embed-subs")
+	}
+	return m.WithOptions(options)
+}
+
+// Removes sponsor segments in the given SponsorBlock categories from the download, e.g. []string{"sponsor", "selfpromo"}
+func (m *YoutubeDl) WithSponsorBlock(
+	categories []string,
+) *YoutubeDl {
+	return m.WithOptions([]string{"--sponsorblock-remove", strings.Join(categories, ",")})
+}
+
+// Authenticates using cookies exported from the given local browser, e.g. "chrome", "firefox"
+func (m *YoutubeDl) WithCookiesFromBrowser(
+	browser string,
+) *YoutubeDl {
+	return m.WithOptions([]string{"--cookies-from-browser", browser})
+}
+
+// Restricts a playlist download to the given index range, e.g. "1-5,8,10:"
+func (m *YoutubeDl) WithPlaylist(
+	playlistRange string,
+) *YoutubeDl {
+	return m.WithOptions([]string{"--yes-playlist", "--playlist-items", playlistRange})
+}
+
+// Downloads every video in a playlist, fanning out one container per video so the engine can
+// run the downloads concurrently instead of sequentially.
+func (m *YoutubeDl) Playlist(ctx context.Context, url string) (*dagger.Directory, error) {
+
+	listing, err := m.Cli.
+		WithExec([]string{"--flat-playlist", "--dump-json", url}, WithExecOpts).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list playlist: %w", err)
+	}
+
+	result := dag.Directory()
+
+	for _, line := range strings.Split(strings.TrimSpace(listing), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var entry struct {
+			ID  string `json:"id"`
+			URL string `json:"url"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse playlist entry: %w", err)
+		}
+
+		videoURL := entry.URL
+		if videoURL == "" {
+			videoURL = fmt.Sprintf("https://www.youtube.com/watch?v=%s", entry.ID)
+		}
+
+		videoDir := m.Cli.
+			WithExec(append(m.Options, videoURL), WithExecOpts).
+			Directory(".")
+
+		result = result.WithDirectory(entry.ID, videoDir)
+	}
+
+	return result, nil
+}
+
+// WithArchive plumbs --download-archive through a cache volume, so running the same YoutubeDl
+// pipeline repeatedly across scheduled invocations only fetches items not already recorded in
+// the archive. An existing archive file can optionally be seeded in on first use.
+func (m *YoutubeDl) WithArchive(
+	// An existing download-archive file to seed the cache volume with
+	// +optional
+	archive *dagger.File,
+) *YoutubeDl {
+	cache := dag.CacheVolume("youtube-dl-archive")
+	m.Cli = m.Cli.WithMountedCache("/app/archive", cache)
+
+	if archive != nil {
+		m.Cli = m.Cli.
+			WithMountedFile("/app/archive/seed.txt", archive).
+			WithExec([]string{"sh", "-c", "test -f /app/archive/archive.txt || mv /app/archive/seed.txt /app/archive/archive.txt"})
+	}
+
+	return m.WithOptions([]string{"--download-archive", "/app/archive/archive.txt"})
+}
+
+// Archive returns the download-archive file as it stands after running the configured options
+// and URLs, reflecting anything WithArchive recorded as already downloaded.
+func (m *YoutubeDl) Archive() *dagger.File {
+	return m.Cli.WithExec(append(m.Options, m.Urls...), WithExecOpts).File("/app/archive/archive.txt")
+}
+
+// Dumps metadata for the current URLs as JSON without downloading anything
+func (m *YoutubeDl) Info(ctx context.Context) (dagger.JSON, error) {
+	out, err := m.Cli.
+		WithExec(append([]string{"--dump-single-json", "--no-warnings"}, m.Urls...), WithExecOpts).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch video info: %w", err)
+	}
+	return dagger.JSON(out), nil
+}
+
 // Download the video from the given URL
 func (m *YoutubeDl) File(
 	// The file to save the video to